@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// extraManifest pairs a decoded object with the Kind the scheme decoder
+// discovered for it, so passthrough output can be named and serialized
+// without re-inspecting the object.
+type extraManifest struct {
+	kind   string
+	name   string
+	object runtime.Object
+}
+
+var yamlSerializer = json.NewYAMLSerializer(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme)
+
+// writeExtraManifestsToDir writes each manifest the CSV install strategy
+// didn't consume to its own <name>_<lowercase-kind>.yaml file under dir,
+// mirroring the manifests/ directory layout operator-sdk's "generate
+// bundle"/"generate packagemanifests" commands produce for non-CSV bundle
+// content.
+func writeExtraManifestsToDir(extras []extraManifest, dir string) error {
+	if len(extras) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, extra := range extras {
+		fileName := fmt.Sprintf("%s_%s.yaml", extra.name, strings.ToLower(extra.kind))
+		f, err := os.Create(filepath.Join(dir, fileName))
+		if err != nil {
+			return err
+		}
+		if err := yamlSerializer.Encode(extra.object, f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStdoutBundle concatenates the generated CSV and every unconsumed
+// extra manifest onto w as a single "---"-delimited YAML stream, making the
+// tool's output a drop-in replacement for a bundle manifests/ directory.
+func writeStdoutBundle(csv *operatorsv1alpha1.ClusterServiceVersion, extras []extraManifest, w io.Writer) error {
+	if err := yamlSerializer.Encode(csv, w); err != nil {
+		return err
+	}
+	for _, extra := range extras {
+		if _, err := fmt.Fprintln(w, "---"); err != nil {
+			return err
+		}
+		if err := yamlSerializer.Encode(extra.object, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	// displayNameAnnotation lets a CRD author hint at the owned CRD's
+	// CSV DisplayName without having to author a --csv-base.
+	displayNameAnnotation = "operators.operatorframework.io/display-name"
+	// resourcesAnnotation carries a comma-separated "Kind/Version" list
+	// that's surfaced as the owned CRD's Resources.
+	resourcesAnnotation = "operators.operatorframework.io/resources"
+)
+
+// crdDescriptionsFor builds one CRDDescription per served version declared
+// on crd, using crd.Name ("<plural>.<group>"), crd.Spec.Names.Kind and the
+// served version's own name rather than the CustomResourceDefinition object's own apiVersion/kind -
+// OLM expects the group/version/kind of the custom resource the CRD
+// describes, not of the CRD manifest itself. When populateDescriptors is
+// true, the OpenAPI v3 schema for each served version is walked to
+// auto-populate SpecDescriptors/StatusDescriptors from the properties under
+// .spec/.status.
+func crdDescriptionsFor(crd *apiextensionsv1.CustomResourceDefinition, populateDescriptors bool) []operatorsv1alpha1.CRDDescription {
+	var descriptions []operatorsv1alpha1.CRDDescription
+
+	displayName := crd.Annotations[displayNameAnnotation]
+	resources := parseResourcesAnnotation(crd.Annotations[resourcesAnnotation])
+
+	for _, version := range crd.Spec.Versions {
+		if !version.Served {
+			continue
+		}
+
+		description := operatorsv1alpha1.CRDDescription{
+			Name:        crd.Name,
+			Version:     version.Name,
+			Kind:        crd.Spec.Names.Kind,
+			DisplayName: displayName,
+			Resources:   resources,
+		}
+
+		if populateDescriptors && version.Schema != nil && version.Schema.OpenAPIV3Schema != nil {
+			description.SpecDescriptors = specDescriptorsFor(version.Schema.OpenAPIV3Schema.Properties["spec"])
+			description.StatusDescriptors = statusDescriptorsFor(version.Schema.OpenAPIV3Schema.Properties["status"])
+		}
+
+		descriptions = append(descriptions, description)
+	}
+
+	return descriptions
+}
+
+// parseResourcesAnnotation parses a "Kind/Version,Kind/Version" annotation
+// value into APIResourceReference entries.
+func parseResourcesAnnotation(value string) []operatorsv1alpha1.APIResourceReference {
+	if value == "" {
+		return nil
+	}
+
+	var resources []operatorsv1alpha1.APIResourceReference
+	for _, entry := range strings.Split(value, ",") {
+		kindAndVersion := strings.SplitN(strings.TrimSpace(entry), "/", 2)
+		if len(kindAndVersion) != 2 || kindAndVersion[0] == "" || kindAndVersion[1] == "" {
+			continue
+		}
+		resources = append(resources, operatorsv1alpha1.APIResourceReference{
+			Kind:    kindAndVersion[0],
+			Version: kindAndVersion[1],
+		})
+	}
+	return resources
+}
+
+// specDescriptorsFor builds a SpecDescriptor per property of schema, using
+// the property name as both the descriptor's JSONPath and DisplayName, and
+// the property's own description - the same name -> JSONPath,
+// description -> description convention operator-sdk's CSV base generator
+// follows.
+func specDescriptorsFor(schema apiextensionsv1.JSONSchemaProps) []operatorsv1alpha1.SpecDescriptor {
+	var descriptors []operatorsv1alpha1.SpecDescriptor
+	for _, name := range sortedPropertyNames(schema) {
+		descriptors = append(descriptors, operatorsv1alpha1.SpecDescriptor{
+			Path:        name,
+			DisplayName: name,
+			Description: schema.Properties[name].Description,
+		})
+	}
+	return descriptors
+}
+
+// statusDescriptorsFor builds a StatusDescriptor per property of schema,
+// following the same convention as specDescriptorsFor.
+func statusDescriptorsFor(schema apiextensionsv1.JSONSchemaProps) []operatorsv1alpha1.StatusDescriptor {
+	var descriptors []operatorsv1alpha1.StatusDescriptor
+	for _, name := range sortedPropertyNames(schema) {
+		descriptors = append(descriptors, operatorsv1alpha1.StatusDescriptor{
+			Path:        name,
+			DisplayName: name,
+			Description: schema.Properties[name].Description,
+		})
+	}
+	return descriptors
+}
+
+// stripSchemaDescriptions recursively zeroes out Description fields under
+// schema. This replaces the previous strings.ReplaceAll(data, "---", "")
+// hack, which corrupted any manifest (not just CRDs) whose description text
+// happened to contain "---".
+func stripSchemaDescriptions(schema *apiextensionsv1.JSONSchemaProps) {
+	if schema == nil {
+		return
+	}
+
+	schema.Description = ""
+
+	for name, prop := range schema.Properties {
+		stripSchemaDescriptions(&prop)
+		schema.Properties[name] = prop
+	}
+
+	if schema.Items != nil {
+		stripSchemaDescriptions(schema.Items.Schema)
+		for i := range schema.Items.JSONSchemas {
+			stripSchemaDescriptions(&schema.Items.JSONSchemas[i])
+		}
+	}
+
+	if schema.AdditionalProperties != nil {
+		stripSchemaDescriptions(schema.AdditionalProperties.Schema)
+	}
+}
+
+func sortedPropertyNames(schema apiextensionsv1.JSONSchemaProps) []string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
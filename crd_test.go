@@ -0,0 +1,187 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newCRD(name, group, kind string, versions ...apiextensionsv1.CustomResourceDefinitionVersion) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:    group,
+			Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: kind},
+			Versions: versions,
+		},
+	}
+}
+
+func TestCRDDescriptionsFor(t *testing.T) {
+	t.Run("only served versions produce a CRDDescription", func(t *testing.T) {
+		crd := newCRD("foos.example.com", "example.com", "Foo",
+			apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1alpha1", Served: false, Storage: false},
+			apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1", Served: true, Storage: true},
+		)
+
+		got := crdDescriptionsFor(crd, false)
+
+		want := []operatorsv1alpha1.CRDDescription{
+			{Name: "foos.example.com", Version: "v1", Kind: "Foo"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("crdDescriptionsFor() = %#v, want %#v", got, want)
+		}
+	})
+
+	t.Run("missing schema does not panic and yields no descriptors", func(t *testing.T) {
+		crd := newCRD("foos.example.com", "example.com", "Foo",
+			apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1", Served: true, Storage: true},
+		)
+
+		got := crdDescriptionsFor(crd, true)
+
+		if len(got) != 1 {
+			t.Fatalf("expected exactly one CRDDescription, got %d", len(got))
+		}
+		if len(got[0].SpecDescriptors) != 0 || len(got[0].StatusDescriptors) != 0 {
+			t.Fatalf("expected no descriptors without a schema, got %#v", got[0])
+		}
+	})
+
+	t.Run("populateDescriptors=false skips walking the schema even when present", func(t *testing.T) {
+		crd := newCRD("foos.example.com", "example.com", "Foo", apiextensionsv1.CustomResourceDefinitionVersion{
+			Name: "v1", Served: true, Storage: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"spec": {Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"replicas": {Description: "desired replica count"},
+						}},
+					},
+				},
+			},
+		})
+
+		got := crdDescriptionsFor(crd, false)
+
+		if len(got[0].SpecDescriptors) != 0 {
+			t.Fatalf("expected no SpecDescriptors when populateDescriptors is false, got %#v", got[0].SpecDescriptors)
+		}
+	})
+
+	t.Run("nested spec/status properties populate descriptors by name", func(t *testing.T) {
+		crd := newCRD("foos.example.com", "example.com", "Foo", apiextensionsv1.CustomResourceDefinitionVersion{
+			Name: "v1", Served: true, Storage: true,
+			Schema: &apiextensionsv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+					Properties: map[string]apiextensionsv1.JSONSchemaProps{
+						"spec": {Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"replicas": {Description: "desired replica count"},
+							"image":    {Description: "container image"},
+						}},
+						"status": {Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"phase": {Description: "current phase"},
+						}},
+					},
+				},
+			},
+		})
+
+		got := crdDescriptionsFor(crd, true)
+
+		wantSpec := []operatorsv1alpha1.SpecDescriptor{
+			{Path: "image", DisplayName: "image", Description: "container image"},
+			{Path: "replicas", DisplayName: "replicas", Description: "desired replica count"},
+		}
+		if !reflect.DeepEqual(got[0].SpecDescriptors, wantSpec) {
+			t.Fatalf("SpecDescriptors = %#v, want %#v", got[0].SpecDescriptors, wantSpec)
+		}
+
+		wantStatus := []operatorsv1alpha1.StatusDescriptor{
+			{Path: "phase", DisplayName: "phase", Description: "current phase"},
+		}
+		if !reflect.DeepEqual(got[0].StatusDescriptors, wantStatus) {
+			t.Fatalf("StatusDescriptors = %#v, want %#v", got[0].StatusDescriptors, wantStatus)
+		}
+	})
+
+	t.Run("display-name and resources annotations are surfaced", func(t *testing.T) {
+		crd := newCRD("foos.example.com", "example.com", "Foo",
+			apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1", Served: true, Storage: true},
+		)
+		crd.Annotations = map[string]string{
+			displayNameAnnotation: "Foo Operator",
+			resourcesAnnotation:   "Pod/v1, Service/v1",
+		}
+
+		got := crdDescriptionsFor(crd, false)
+
+		if got[0].DisplayName != "Foo Operator" {
+			t.Fatalf("DisplayName = %q, want %q", got[0].DisplayName, "Foo Operator")
+		}
+		want := []operatorsv1alpha1.APIResourceReference{
+			{Kind: "Pod", Version: "v1"},
+			{Kind: "Service", Version: "v1"},
+		}
+		if !reflect.DeepEqual(got[0].Resources, want) {
+			t.Fatalf("Resources = %#v, want %#v", got[0].Resources, want)
+		}
+	})
+}
+
+func TestStripSchemaDescriptions(t *testing.T) {
+	schema := &apiextensionsv1.JSONSchemaProps{
+		Description: "top level",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"spec": {
+				Description: "spec level",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"replicas": {Description: "desired replica count"},
+				},
+			},
+		},
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+			Schema: &apiextensionsv1.JSONSchemaProps{Description: "item level"},
+		},
+	}
+
+	stripSchemaDescriptions(schema)
+
+	if schema.Description != "" {
+		t.Fatalf("top-level Description not stripped: %q", schema.Description)
+	}
+	if schema.Properties["spec"].Description != "" {
+		t.Fatalf("nested Description not stripped: %q", schema.Properties["spec"].Description)
+	}
+	if schema.Properties["spec"].Properties["replicas"].Description != "" {
+		t.Fatalf("doubly-nested Description not stripped: %q", schema.Properties["spec"].Properties["replicas"].Description)
+	}
+	if schema.Items.Schema.Description != "" {
+		t.Fatalf("Items.Schema Description not stripped: %q", schema.Items.Schema.Description)
+	}
+}
+
+func TestParseResourcesAnnotation(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []operatorsv1alpha1.APIResourceReference
+	}{
+		{name: "empty value", value: "", want: nil},
+		{name: "single entry", value: "Pod/v1", want: []operatorsv1alpha1.APIResourceReference{{Kind: "Pod", Version: "v1"}}},
+		{name: "malformed entry is skipped", value: "Pod,Service/v1", want: []operatorsv1alpha1.APIResourceReference{{Kind: "Service", Version: "v1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseResourcesAnnotation(tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseResourcesAnnotation(%q) = %#v, want %#v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
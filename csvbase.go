@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+)
+
+// loadCSVBase decodes the ClusterServiceVersion at path, following the
+// "base" pattern `operator-sdk generate kustomize manifests` produces:
+// hand-authored presentation fields (spec.displayName, spec.description,
+// spec.icon, spec.keywords, spec.maintainers, spec.links, spec.version,
+// spec.maturity, spec.minKubeVersion, owned CRD descriptor metadata) are
+// preserved while the discovered install strategy, permissions, and served
+// CRD versions are overlaid on top by the caller.
+func loadCSVBase(path string) (*operatorsv1alpha1.ClusterServiceVersion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the %s CSV base: %v", path, err)
+	}
+
+	base := &operatorsv1alpha1.ClusterServiceVersion{}
+	if _, _, err := yamlSerializer.Decode(data, nil, base); err != nil {
+		return nil, fmt.Errorf("failed to decode the %s CSV base: %v", path, err)
+	}
+	return base, nil
+}
+
+// mergeOwnedCRDDescriptions overlays discovered CRDDescriptions onto the
+// ones already present in base (matched by (group, kind)), preserving any
+// hand-authored Description/DisplayName/Resources/SpecDescriptors/
+// StatusDescriptors the user already filled in while refreshing the served
+// Version the generator just discovered. Base entries whose (group, kind)
+// wasn't re-observed in this run are carried forward unchanged, rather than
+// dropped, since --manifests may only cover a subset of the CRDs the
+// --csv-base already owns.
+func mergeOwnedCRDDescriptions(base, discovered []operatorsv1alpha1.CRDDescription) []operatorsv1alpha1.CRDDescription {
+	baseByKey := make(map[string]operatorsv1alpha1.CRDDescription, len(base))
+	for _, b := range base {
+		baseByKey[crdDescriptionKey(b)] = b
+	}
+
+	discoveredKeys := make(map[string]bool, len(discovered))
+	merged := make([]operatorsv1alpha1.CRDDescription, 0, len(base)+len(discovered))
+	for _, d := range discovered {
+		key := crdDescriptionKey(d)
+		discoveredKeys[key] = true
+		if b, ok := baseByKey[key]; ok {
+			d.DisplayName = firstNonEmpty(b.DisplayName, d.DisplayName)
+			d.Description = firstNonEmpty(b.Description, d.Description)
+			if len(b.Resources) != 0 {
+				d.Resources = b.Resources
+			}
+			if len(b.SpecDescriptors) != 0 {
+				d.SpecDescriptors = b.SpecDescriptors
+			}
+			if len(b.StatusDescriptors) != 0 {
+				d.StatusDescriptors = b.StatusDescriptors
+			}
+		}
+		merged = append(merged, d)
+	}
+
+	for _, b := range base {
+		if !discoveredKeys[crdDescriptionKey(b)] {
+			merged = append(merged, b)
+		}
+	}
+
+	return merged
+}
+
+// crdDescriptionKey builds a (group, kind) matching key out of a
+// CRDDescription's Name (which is always "<plural>.<group>") and Kind.
+func crdDescriptionKey(d operatorsv1alpha1.CRDDescription) string {
+	group := d.Name
+	if i := strings.Index(d.Name, "."); i >= 0 {
+		group = d.Name[i+1:]
+	}
+	return group + "/" + d.Kind
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
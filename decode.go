@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"io"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// splitYAMLDocuments tokenizes a multi-document YAML/JSON stream into its
+// constituent raw documents using the YAML parser itself, rather than
+// naively splitting on "---" - which corrupts any document (Secret data,
+// ConfigMap bodies, Helm-rendered output) that happens to embed a literal
+// "---" in a string or block scalar value.
+func splitYAMLDocuments(data []byte) ([][]byte, error) {
+	var docs [][]byte
+
+	docReader := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var doc runtime.RawExtension
+		if err := docReader.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(doc.Raw) == 0 {
+			continue
+		}
+		docs = append(docs, doc.Raw)
+	}
+
+	return docs, nil
+}
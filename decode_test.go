@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestSplitYAMLDocuments(t *testing.T) {
+	t.Run("document containing a literal --- in its data is not split", func(t *testing.T) {
+		stream := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: banner
+data:
+  motd: |
+    welcome
+    ---
+    have a nice day
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: controller-manager
+`
+
+		docs, err := splitYAMLDocuments([]byte(stream))
+		if err != nil {
+			t.Fatalf("splitYAMLDocuments() returned error: %v", err)
+		}
+		if len(docs) != 2 {
+			t.Fatalf("expected 2 documents, got %d: %q", len(docs), docs)
+		}
+
+		apiextensionsv1.AddToScheme(scheme.Scheme)
+		decoder := scheme.Codecs.UniversalDeserializer()
+
+		obj, gvk, err := decoder.Decode(docs[0], nil, nil)
+		if err != nil {
+			t.Fatalf("failed to decode first document: %v", err)
+		}
+		if gvk.Kind != "ConfigMap" {
+			t.Fatalf("first document Kind = %q, want %q", gvk.Kind, "ConfigMap")
+		}
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			t.Fatalf("first document is a %T, want *corev1.ConfigMap", obj)
+		}
+		wantMOTD := "welcome\n---\nhave a nice day\n"
+		if cm.Data["motd"] != wantMOTD {
+			t.Fatalf("motd = %q, want %q (embedded --- must survive tokenization)", cm.Data["motd"], wantMOTD)
+		}
+
+		_, gvk, err = decoder.Decode(docs[1], nil, nil)
+		if err != nil {
+			t.Fatalf("failed to decode second document: %v", err)
+		}
+		if gvk.Kind != "ServiceAccount" {
+			t.Fatalf("second document Kind = %q, want %q", gvk.Kind, "ServiceAccount")
+		}
+	})
+
+	t.Run("empty documents from leading/trailing separators are skipped", func(t *testing.T) {
+		stream := "---\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: foo\n---\n"
+
+		docs, err := splitYAMLDocuments([]byte(stream))
+		if err != nil {
+			t.Fatalf("splitYAMLDocuments() returned error: %v", err)
+		}
+		if len(docs) != 1 {
+			t.Fatalf("expected 1 document, got %d: %q", len(docs), docs)
+		}
+	})
+}
@@ -3,10 +3,8 @@ package main
 import (
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
 
 	operatorsv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	"github.com/sirupsen/logrus"
@@ -16,16 +14,19 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
 type Options struct {
-	manifestDir      string
-	stripDescriptors bool
-	outputFile       string
-	logLevel         string
-	csvName          string
+	manifestDir       string
+	stripDescriptors  bool
+	outputFile        string
+	logLevel          string
+	csvName           string
+	extraManifestsDir string
+	stdout            bool
+	csvBase           string
+	values            []string
 }
 
 func newRunCmd() *cobra.Command {
@@ -34,11 +35,15 @@ func newRunCmd() *cobra.Command {
 		Use:  "migrate",
 		RunE: o.Run,
 	}
-	cmd.Flags().StringVar(&o.manifestDir, "manifests", "./manifests", "path to the manifests directory")
+	cmd.Flags().StringVar(&o.manifestDir, "manifests", "./manifests", "path to the manifests source: a directory of raw YAML, a Kustomize root (containing a kustomization.yaml), or a Helm chart (directory or .tgz, containing a Chart.yaml)")
 	cmd.Flags().StringVar(&o.outputFile, "output-file", "", "configures the output file for the generated CSV")
 	cmd.Flags().StringVar(&o.logLevel, "log-level", logrus.InfoLevel.String(), "log level")
 	cmd.Flags().StringVar(&o.csvName, "csv-name", "", "configures the metadata.Name of the generated CSV")
-	cmd.Flags().BoolVar(&o.stripDescriptors, "strip-descriptors", true, "controls whether CRD descriptions will be stripped when processing a CRD YAML manifest")
+	cmd.Flags().BoolVar(&o.stripDescriptors, "strip-descriptors", true, "controls whether a decoded CRD's Spec.Versions[*].Schema descriptions are zeroed out before building its CRDDescription")
+	cmd.Flags().StringVar(&o.extraManifestsDir, "extra-manifests-dir", "", "directory to write manifests not consumed by the CSV install strategy to, as a sibling bundle (defaults to the directory of --output-file)")
+	cmd.Flags().BoolVar(&o.stdout, "stdout", false, "write the generated CSV and any extra manifests to stdout as a single '---'-separated YAML stream, instead of --output-file/--extra-manifests-dir")
+	cmd.Flags().StringVar(&o.csvBase, "csv-base", "", "path to an existing ClusterServiceVersion to use as a base, overlaying the discovered install strategy, permissions, and owned CRDs on top of its hand-authored fields")
+	cmd.Flags().StringArrayVar(&o.values, "values", nil, "values files to apply when --manifests points at a Helm chart (repeatable)")
 
 	if err := cmd.MarkFlagRequired("csv-name"); err != nil {
 		panic(err)
@@ -57,11 +62,12 @@ func main() {
 
 func (o *Options) Run(cmd *cobra.Command, args []string) error {
 	var (
-		saName          string
-		crRules         []rbacv1.PolicyRule
-		roleRules       []rbacv1.PolicyRule
-		descriptions    []operatorsv1alpha1.CRDDescription
-		deploymentSpecs []operatorsv1alpha1.StrategyDeploymentSpec
+		serviceAccounts           = map[string]*corev1.ServiceAccount{}
+		referencedServiceAccounts = map[string]bool{}
+		rbac                      = newRBACCollector()
+		descriptions              []operatorsv1alpha1.CRDDescription
+		deploymentSpecs           []operatorsv1alpha1.StrategyDeploymentSpec
+		candidates                []extraManifest
 	)
 
 	logger := logrus.WithFields(logrus.Fields{
@@ -77,121 +83,178 @@ func (o *Options) Run(cmd *cobra.Command, args []string) error {
 	apiextensionsv1.AddToScheme(scheme.Scheme)
 	decoder := scheme.Codecs.UniversalDeserializer()
 
-	fsys := os.DirFS(o.manifestDir)
 	csv := &operatorsv1alpha1.ClusterServiceVersion{}
+	if o.csvBase != "" {
+		csv, err = loadCSVBase(o.csvBase)
+		if err != nil {
+			return err
+		}
+	}
 	csv.TypeMeta = metav1.TypeMeta{
 		APIVersion: operatorsv1alpha1.ClusterServiceVersionAPIVersion,
 		Kind:       operatorsv1alpha1.ClusterServiceVersionKind,
 	}
 	csv.SetName(o.csvName)
 
-	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
-		if filepath.Ext(path) != ".yaml" {
-			return nil
-		}
+	data, err := renderManifestSource(o)
+	if err != nil {
+		return err
+	}
 
-		data, err := fs.ReadFile(fsys, path)
-		if err != nil {
-			return err
-		}
+	docs, err := splitYAMLDocuments(data)
+	if err != nil {
+		return fmt.Errorf("failed to tokenize the %s manifests: %v", o.manifestDir, err)
+	}
 
-		// Note: this is a super hacky way of ensuring we can still process multi-document
-		// YAML manifests, and working around CRD descriptors that contain field descriptors
-		// that contain the "---" separating character that controller-gen will populate.
-		// TODO: there's likely a much better implementation but that would require using my brain.
-		dataStr := string(data)
-		if o.stripDescriptors && strings.Contains(string(data), "CustomResourceDefinition") {
-			dataStr = strings.ReplaceAll(dataStr, "---", "")
+	for _, doc := range docs {
+		obj, gvk, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			logger.Warnf("failed to decode manifest: %v", err)
+			continue
 		}
-		resources := strings.Split(dataStr, "---")
 
-		for _, resource := range resources {
-			if len(resource) == 0 {
+		switch gvk.Kind {
+		case "Deployment":
+			deployment, ok := obj.(*appsv1.Deployment)
+			if !ok {
+				continue
+			}
+			deploymentSpecs = append(deploymentSpecs, operatorsv1alpha1.StrategyDeploymentSpec{
+				Name: deployment.GetName(),
+				Spec: deployment.Spec,
+			})
+			if saName := deployment.Spec.Template.Spec.ServiceAccountName; saName != "" {
+				referencedServiceAccounts[saName] = true
+			}
+		case "ServiceAccount":
+			sa, ok := obj.(*corev1.ServiceAccount)
+			if !ok {
 				continue
 			}
-			obj, gvk, err := decoder.Decode([]byte(resource), nil, nil)
-			if err != nil {
-				logger.Warnf("failed to decode manifest", path)
+			serviceAccounts[sa.GetName()] = sa
+			candidates = append(candidates, extraManifest{kind: gvk.Kind, name: sa.GetName(), object: sa})
+		case "ClusterRole":
+			cr, ok := obj.(*rbacv1.ClusterRole)
+			if !ok {
 				continue
 			}
-
-			switch gvk.Kind {
-			case "Deployment":
-				deployment, ok := obj.(*appsv1.Deployment)
-				if !ok {
-					continue
-				}
-				deploymentSpecs = append(deploymentSpecs, operatorsv1alpha1.StrategyDeploymentSpec{
-					Name: deployment.GetName(),
-					Spec: deployment.Spec,
-				})
-			case "ServiceAccount":
-				sa, ok := obj.(*corev1.ServiceAccount)
-				if !ok {
-					continue
-				}
-				saName = sa.GetName()
-			case "ClusterRole":
-				cr, ok := obj.(*rbacv1.ClusterRole)
-				if !ok {
-					continue
-				}
-				crRules = append(crRules, cr.Rules...)
-			case "Role":
-				role, ok := obj.(*rbacv1.Role)
-				if !ok {
-					continue
-				}
-				roleRules = append(roleRules, role.Rules...)
-			case "CustomResourceDefinition":
-				crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
-				if !ok {
-					continue
+			rbac.addClusterRole(cr)
+			candidates = append(candidates, extraManifest{kind: gvk.Kind, name: cr.GetName(), object: cr})
+		case "Role":
+			role, ok := obj.(*rbacv1.Role)
+			if !ok {
+				continue
+			}
+			rbac.addRole(role)
+			candidates = append(candidates, extraManifest{kind: gvk.Kind, name: role.GetName(), object: role})
+		case "RoleBinding":
+			rb, ok := obj.(*rbacv1.RoleBinding)
+			if !ok {
+				continue
+			}
+			rbac.addRoleBinding(rb)
+			candidates = append(candidates, extraManifest{kind: gvk.Kind, name: rb.GetName(), object: rb})
+		case "ClusterRoleBinding":
+			crb, ok := obj.(*rbacv1.ClusterRoleBinding)
+			if !ok {
+				continue
+			}
+			rbac.addClusterRoleBinding(crb)
+			candidates = append(candidates, extraManifest{kind: gvk.Kind, name: crb.GetName(), object: crb})
+		case "CustomResourceDefinition":
+			crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition)
+			if !ok {
+				continue
+			}
+			if o.stripDescriptors {
+				for i := range crd.Spec.Versions {
+					if crd.Spec.Versions[i].Schema != nil {
+						stripSchemaDescriptions(crd.Spec.Versions[i].Schema.OpenAPIV3Schema)
+					}
 				}
-				descriptions = append(descriptions, operatorsv1alpha1.CRDDescription{
-					Name:    crd.Name,
-					Version: crd.APIVersion,
-					Kind:    crd.Kind,
-				})
+			}
+			descriptions = append(descriptions, crdDescriptionsFor(crd, !o.stripDescriptors)...)
+			candidates = append(candidates, extraManifest{kind: gvk.Kind, name: crd.GetName(), object: crd})
+		default:
+			// Not consumed by the CSV install strategy at all (Secrets,
+			// ConfigMaps, Services, etc.) - always passed through as a
+			// sibling bundle manifest.
+			if m, ok := obj.(metav1.Object); ok {
+				candidates = append(candidates, extraManifest{kind: gvk.Kind, name: m.GetName(), object: obj})
 			}
 		}
-		return nil
-	})
-	if err != nil {
-		return err
 	}
 
-	if saName == "" {
+	if len(serviceAccounts) == 0 {
 		return fmt.Errorf("validation: unable to find a ServiceAccount manifest in the %s directory", o.manifestDir)
 	}
 
-	// TODO: clean this implementation up
-	if len(crRules) != 0 {
-		csv.Spec.InstallStrategy.StrategySpec.ClusterPermissions = []operatorsv1alpha1.StrategyDeploymentPermissions{
-			{
-				ServiceAccountName: saName,
-				Rules:              crRules,
-			},
-		}
+	// Resolve Role/ClusterRole rules to the ServiceAccounts they're actually
+	// bound to via RoleBinding/ClusterRoleBinding subjects, rather than
+	// attributing every discovered rule to whichever ServiceAccount manifest
+	// happened to be walked last.
+	permsBySA, boundRoles, boundClusterRolesViaRB := rbac.permissionsBySA()
+	clusterPermsBySA, boundClusterRolesViaCRB := rbac.clusterPermissionsBySA()
+	boundClusterRoles := mergeBoolSets(boundClusterRolesViaRB, boundClusterRolesViaCRB)
+
+	var permissions []operatorsv1alpha1.StrategyDeploymentPermissions
+	for _, name := range sortedSAKeys(permsBySA) {
+		permissions = append(permissions, operatorsv1alpha1.StrategyDeploymentPermissions{
+			ServiceAccountName: name,
+			Rules:              permsBySA[name],
+		})
 	}
-	if len(roleRules) != 0 {
-		csv.Spec.InstallStrategy.StrategySpec.Permissions = []operatorsv1alpha1.StrategyDeploymentPermissions{
-			{
-				ServiceAccountName: saName,
-				Rules:              roleRules,
-			},
-		}
+	if len(permissions) != 0 {
+		csv.Spec.InstallStrategy.StrategySpec.Permissions = permissions
 	}
-	if len(descriptions) != 0 {
-		csv.Spec.CustomResourceDefinitions = operatorsv1alpha1.CustomResourceDefinitions{
-			Owned: descriptions,
-		}
+
+	var clusterPermissions []operatorsv1alpha1.StrategyDeploymentPermissions
+	for _, name := range sortedSAKeys(clusterPermsBySA) {
+		clusterPermissions = append(clusterPermissions, operatorsv1alpha1.StrategyDeploymentPermissions{
+			ServiceAccountName: name,
+			Rules:              clusterPermsBySA[name],
+		})
+	}
+	if len(clusterPermissions) != 0 {
+		csv.Spec.InstallStrategy.StrategySpec.ClusterPermissions = clusterPermissions
+	}
+
+	if merged := mergeOwnedCRDDescriptions(csv.Spec.CustomResourceDefinitions.Owned, descriptions); len(merged) != 0 {
+		csv.Spec.CustomResourceDefinitions.Owned = merged
 	}
 	if len(deploymentSpecs) != 0 {
 		csv.Spec.InstallStrategy.StrategyName = "deployment"
 		csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = deploymentSpecs
 	}
 
+	// Everything the CSV install strategy didn't consume - ServiceAccounts no
+	// Deployment references, unbound Roles/ClusterRoles, RoleBindings/ClusterRoleBindings,
+	// and any other manifest kind - gets written out as a sibling bundle
+	// rather than silently dropped.
+	var extras []extraManifest
+	for _, candidate := range candidates {
+		switch candidate.kind {
+		case "ServiceAccount":
+			if referencedServiceAccounts[candidate.name] {
+				continue
+			}
+		case "Role":
+			if boundRoles[candidate.name] {
+				continue
+			}
+		case "ClusterRole":
+			if boundClusterRoles[candidate.name] {
+				continue
+			}
+		}
+		extras = append(extras, candidate)
+	}
+
+	if o.stdout {
+		logger.Debugf("writing the generated CSV and %d extra manifest(s) to stdout", len(extras))
+		return writeStdoutBundle(csv, extras, os.Stdout)
+	}
+
 	outputFile := os.Stdout
 	if o.outputFile != "" {
 		_, err := os.Stat(o.outputFile)
@@ -209,8 +272,21 @@ func (o *Options) Run(cmd *cobra.Command, args []string) error {
 
 	// TODO: handle case where empty fields are being encoded
 	logger.Debugf("creating the generated CSV at the %v file", outputFile.Name())
-	s := json.NewYAMLSerializer(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme)
-	if err := s.Encode(csv, outputFile); err != nil {
+	if err := yamlSerializer.Encode(csv, outputFile); err != nil {
+		return err
+	}
+
+	extraManifestsDir := o.extraManifestsDir
+	if extraManifestsDir == "" {
+		if o.outputFile != "" {
+			extraManifestsDir = filepath.Dir(o.outputFile)
+		} else {
+			extraManifestsDir = "."
+		}
+	}
+
+	logger.Debugf("writing %d extra manifest(s) to the %s directory", len(extras), extraManifestsDir)
+	if err := writeExtraManifestsToDir(extras, extraManifestsDir); err != nil {
 		return err
 	}
 
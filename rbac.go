@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// rbacCollector accumulates the Role/ClusterRole/RoleBinding/ClusterRoleBinding
+// manifests discovered while walking the input directory and resolves them
+// into per-ServiceAccount PolicyRule sets, mirroring the (cluster)role ->
+// binding -> subject lookup operator-sdk performs when generating a CSV's
+// install strategy (see operator-framework/operator-sdk#3600).
+type rbacCollector struct {
+	roles               map[string]*rbacv1.Role
+	clusterRoles        map[string]*rbacv1.ClusterRole
+	roleBindings        []*rbacv1.RoleBinding
+	clusterRoleBindings []*rbacv1.ClusterRoleBinding
+}
+
+func newRBACCollector() *rbacCollector {
+	return &rbacCollector{
+		roles:        map[string]*rbacv1.Role{},
+		clusterRoles: map[string]*rbacv1.ClusterRole{},
+	}
+}
+
+func (c *rbacCollector) addRole(role *rbacv1.Role) {
+	c.roles[role.GetName()] = role
+}
+
+func (c *rbacCollector) addClusterRole(cr *rbacv1.ClusterRole) {
+	c.clusterRoles[cr.GetName()] = cr
+}
+
+func (c *rbacCollector) addRoleBinding(rb *rbacv1.RoleBinding) {
+	c.roleBindings = append(c.roleBindings, rb)
+}
+
+func (c *rbacCollector) addClusterRoleBinding(crb *rbacv1.ClusterRoleBinding) {
+	c.clusterRoleBindings = append(c.clusterRoleBindings, crb)
+}
+
+// permissionsBySA resolves namespaced PolicyRules per bound ServiceAccount
+// name by walking RoleBindings, whether they reference a Role or a
+// ClusterRole. The returned boundRoles/boundClusterRoles sets record which
+// (Cluster)Role names were referenced by a RoleBinding with at least one
+// ServiceAccount subject, so callers can tell which roles were left unbound.
+func (c *rbacCollector) permissionsBySA() (bySA map[string][]rbacv1.PolicyRule, boundRoles, boundClusterRoles map[string]bool) {
+	bySA = map[string][]rbacv1.PolicyRule{}
+	boundRoles = map[string]bool{}
+	boundClusterRoles = map[string]bool{}
+
+	for _, rb := range c.roleBindings {
+		var (
+			rules []rbacv1.PolicyRule
+			bound map[string]bool
+		)
+		switch rb.RoleRef.Kind {
+		case "Role":
+			role, ok := c.roles[rb.RoleRef.Name]
+			if !ok {
+				continue
+			}
+			rules = role.Rules
+			bound = boundRoles
+		case "ClusterRole":
+			cr, ok := c.clusterRoles[rb.RoleRef.Name]
+			if !ok {
+				continue
+			}
+			rules = cr.Rules
+			bound = boundClusterRoles
+		default:
+			continue
+		}
+
+		for _, subject := range rb.Subjects {
+			if subject.Kind != rbacv1.ServiceAccountKind {
+				continue
+			}
+			bySA[subject.Name] = append(bySA[subject.Name], rules...)
+			bound[rb.RoleRef.Name] = true
+		}
+	}
+	return bySA, boundRoles, boundClusterRoles
+}
+
+// clusterPermissionsBySA resolves cluster-scoped PolicyRules per bound
+// ServiceAccount name by walking ClusterRoleBindings. The returned
+// boundClusterRoles set records which ClusterRole names were referenced by
+// a ClusterRoleBinding with at least one ServiceAccount subject.
+func (c *rbacCollector) clusterPermissionsBySA() (bySA map[string][]rbacv1.PolicyRule, boundClusterRoles map[string]bool) {
+	bySA = map[string][]rbacv1.PolicyRule{}
+	boundClusterRoles = map[string]bool{}
+
+	for _, crb := range c.clusterRoleBindings {
+		if crb.RoleRef.Kind != "ClusterRole" {
+			continue
+		}
+		cr, ok := c.clusterRoles[crb.RoleRef.Name]
+		if !ok {
+			continue
+		}
+		for _, subject := range crb.Subjects {
+			if subject.Kind != rbacv1.ServiceAccountKind {
+				continue
+			}
+			bySA[subject.Name] = append(bySA[subject.Name], cr.Rules...)
+			boundClusterRoles[crb.RoleRef.Name] = true
+		}
+	}
+	return bySA, boundClusterRoles
+}
+
+// mergeBoolSets returns the union of the given string sets.
+func mergeBoolSets(sets ...map[string]bool) map[string]bool {
+	merged := map[string]bool{}
+	for _, set := range sets {
+		for name := range set {
+			merged[name] = true
+		}
+	}
+	return merged
+}
+
+// sortedSAKeys returns the ServiceAccount names of rulesBySA in sorted order
+// so that generated Permissions/ClusterPermissions entries are deterministic.
+func sortedSAKeys(rulesBySA map[string][]rbacv1.PolicyRule) []string {
+	names := make([]string, 0, len(rulesBySA))
+	for name := range rulesBySA {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRole(name string, rules ...rbacv1.PolicyRule) *rbacv1.Role {
+	return &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: name}, Rules: rules}
+}
+
+func newClusterRole(name string, rules ...rbacv1.PolicyRule) *rbacv1.ClusterRole {
+	return &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: name}, Rules: rules}
+}
+
+func saSubject(name string) rbacv1.Subject {
+	return rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: name}
+}
+
+func roleBinding(roleRefKind, roleRefName string, subjects ...rbacv1.Subject) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		RoleRef:  rbacv1.RoleRef{Kind: roleRefKind, Name: roleRefName},
+		Subjects: subjects,
+	}
+}
+
+func clusterRoleBinding(roleRefName string, subjects ...rbacv1.Subject) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		RoleRef:  rbacv1.RoleRef{Kind: "ClusterRole", Name: roleRefName},
+		Subjects: subjects,
+	}
+}
+
+func TestPermissionsBySA(t *testing.T) {
+	readPods := rbacv1.PolicyRule{Verbs: []string{"get", "list"}, Resources: []string{"pods"}}
+	writeSecrets := rbacv1.PolicyRule{Verbs: []string{"create"}, Resources: []string{"secrets"}}
+
+	t.Run("role with no binding is not attributed to any ServiceAccount", func(t *testing.T) {
+		c := newRBACCollector()
+		c.addRole(newRole("unbound-role", readPods))
+
+		bySA, boundRoles, boundClusterRoles := c.permissionsBySA()
+
+		if len(bySA) != 0 {
+			t.Fatalf("expected no permissions, got %v", bySA)
+		}
+		if boundRoles["unbound-role"] {
+			t.Fatalf("expected unbound-role to not be marked as bound")
+		}
+		if len(boundClusterRoles) != 0 {
+			t.Fatalf("expected no bound ClusterRoles, got %v", boundClusterRoles)
+		}
+	})
+
+	t.Run("role bound to a single ServiceAccount via RoleBinding", func(t *testing.T) {
+		c := newRBACCollector()
+		c.addRole(newRole("pod-reader", readPods))
+		c.addRoleBinding(roleBinding("Role", "pod-reader", saSubject("reader-sa")))
+
+		bySA, boundRoles, _ := c.permissionsBySA()
+
+		if !boundRoles["pod-reader"] {
+			t.Fatalf("expected pod-reader to be marked as bound")
+		}
+		want := map[string][]rbacv1.PolicyRule{"reader-sa": {readPods}}
+		if !reflect.DeepEqual(bySA, want) {
+			t.Fatalf("permissions = %v, want %v", bySA, want)
+		}
+	})
+
+	t.Run("two ServiceAccounts each bound to their own Role are not cross-attributed", func(t *testing.T) {
+		c := newRBACCollector()
+		c.addRole(newRole("pod-reader", readPods))
+		c.addRole(newRole("secret-writer", writeSecrets))
+		c.addRoleBinding(roleBinding("Role", "pod-reader", saSubject("reader-sa")))
+		c.addRoleBinding(roleBinding("Role", "secret-writer", saSubject("writer-sa")))
+
+		bySA, _, _ := c.permissionsBySA()
+
+		want := map[string][]rbacv1.PolicyRule{
+			"reader-sa": {readPods},
+			"writer-sa": {writeSecrets},
+		}
+		if !reflect.DeepEqual(bySA, want) {
+			t.Fatalf("permissions = %v, want %v", bySA, want)
+		}
+	})
+
+	t.Run("RoleBinding referencing a ClusterRole grants namespaced rules and marks the ClusterRole bound", func(t *testing.T) {
+		c := newRBACCollector()
+		c.addClusterRole(newClusterRole("view", readPods))
+		c.addRoleBinding(roleBinding("ClusterRole", "view", saSubject("reader-sa")))
+
+		bySA, _, boundClusterRoles := c.permissionsBySA()
+
+		want := map[string][]rbacv1.PolicyRule{"reader-sa": {readPods}}
+		if !reflect.DeepEqual(bySA, want) {
+			t.Fatalf("permissions = %v, want %v", bySA, want)
+		}
+		if !boundClusterRoles["view"] {
+			t.Fatalf("expected view ClusterRole to be marked as bound")
+		}
+	})
+}
+
+func TestClusterPermissionsBySA(t *testing.T) {
+	adminRules := rbacv1.PolicyRule{Verbs: []string{"*"}, Resources: []string{"*"}}
+
+	t.Run("ClusterRoleBinding attributes cluster rules to the bound ServiceAccount", func(t *testing.T) {
+		c := newRBACCollector()
+		c.addClusterRole(newClusterRole("admin", adminRules))
+		c.addClusterRoleBinding(clusterRoleBinding("admin", saSubject("admin-sa")))
+
+		bySA, boundClusterRoles := c.clusterPermissionsBySA()
+
+		want := map[string][]rbacv1.PolicyRule{"admin-sa": {adminRules}}
+		if !reflect.DeepEqual(bySA, want) {
+			t.Fatalf("cluster permissions = %v, want %v", bySA, want)
+		}
+		if !boundClusterRoles["admin"] {
+			t.Fatalf("expected admin ClusterRole to be marked as bound")
+		}
+	})
+
+	t.Run("unbound ClusterRole is not attributed to any ServiceAccount", func(t *testing.T) {
+		c := newRBACCollector()
+		c.addClusterRole(newClusterRole("unused", adminRules))
+
+		bySA, boundClusterRoles := c.clusterPermissionsBySA()
+
+		if len(bySA) != 0 {
+			t.Fatalf("expected no cluster permissions, got %v", bySA)
+		}
+		if boundClusterRoles["unused"] {
+			t.Fatalf("expected unused ClusterRole to not be marked as bound")
+		}
+	})
+}
+
+func TestServiceAccountBoundViaRoleAndClusterRole(t *testing.T) {
+	readPods := rbacv1.PolicyRule{Verbs: []string{"get", "list"}, Resources: []string{"pods"}}
+	adminRules := rbacv1.PolicyRule{Verbs: []string{"*"}, Resources: []string{"*"}}
+
+	c := newRBACCollector()
+	c.addRole(newRole("pod-reader", readPods))
+	c.addClusterRole(newClusterRole("admin", adminRules))
+	c.addRoleBinding(roleBinding("Role", "pod-reader", saSubject("both-sa")))
+	c.addClusterRoleBinding(clusterRoleBinding("admin", saSubject("both-sa")))
+
+	permsBySA, boundRoles, _ := c.permissionsBySA()
+	clusterPermsBySA, boundClusterRoles := c.clusterPermissionsBySA()
+
+	wantPerms := map[string][]rbacv1.PolicyRule{"both-sa": {readPods}}
+	if !reflect.DeepEqual(permsBySA, wantPerms) {
+		t.Fatalf("permissions = %v, want %v", permsBySA, wantPerms)
+	}
+	wantClusterPerms := map[string][]rbacv1.PolicyRule{"both-sa": {adminRules}}
+	if !reflect.DeepEqual(clusterPermsBySA, wantClusterPerms) {
+		t.Fatalf("cluster permissions = %v, want %v", clusterPermsBySA, wantClusterPerms)
+	}
+	if !boundRoles["pod-reader"] {
+		t.Fatalf("expected pod-reader to be marked as bound")
+	}
+	if !boundClusterRoles["admin"] {
+		t.Fatalf("expected admin to be marked as bound")
+	}
+}
+
+func TestSortedSAKeys(t *testing.T) {
+	rulesBySA := map[string][]rbacv1.PolicyRule{
+		"zebra": nil,
+		"alpha": nil,
+		"mike":  nil,
+	}
+
+	got := sortedSAKeys(rulesBySA)
+	want := []string{"alpha", "mike", "zebra"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sortedSAKeys() = %v, want %v", got, want)
+	}
+}
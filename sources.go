@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// renderManifestSource renders o.manifestDir down to a single "---"
+// delimited YAML stream that can be fed through the same multi-document
+// decode loop regardless of whether it came from a plain directory of YAML,
+// a Kustomize overlay, or a Helm chart. This lets `migrate` run directly
+// against the same sources an operator is deployed from, matching how
+// `operator-sdk generate bundle` consumes `config/manifests`.
+func renderManifestSource(o *Options) ([]byte, error) {
+	switch {
+	case isKustomizeRoot(o.manifestDir):
+		return renderKustomize(o.manifestDir)
+	case isHelmChart(o.manifestDir):
+		return renderHelmChart(o.manifestDir, o.values)
+	default:
+		return renderManifestDir(o.manifestDir)
+	}
+}
+
+func isKustomizeRoot(dir string) bool {
+	for _, name := range []string{"kustomization.yaml", "kustomization.yml", "Kustomization"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isHelmChart(dir string) bool {
+	if filepath.Ext(dir) == ".tgz" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, "Chart.yaml"))
+	return err == nil
+}
+
+// renderManifestDir concatenates every *.yaml file under dir into a single
+// "---" delimited stream, preserving the previous behavior for plain
+// manifest directories.
+func renderManifestDir(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	fsys := os.DirFS(dir)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".yaml" {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+		buf.WriteString("\n")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderKustomize renders the Kustomize root at dir in-process via krusty,
+// the same library `operator-sdk generate kustomize manifests` uses.
+func renderKustomize(dir string) ([]byte, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resMap, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the %s kustomize root: %v", dir, err)
+	}
+	return resMap.AsYaml()
+}
+
+// renderHelmChart renders the Helm chart at path (a chart directory or a
+// packaged .tgz) via the Helm template engine, overlaying any --values
+// files on top of the chart's own defaults.
+func renderHelmChart(path string, valuesFiles []string) ([]byte, error) {
+	chrt, err := loader.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the %s helm chart: %v", path, err)
+	}
+
+	vals := map[string]interface{}{}
+	for _, valuesFile := range valuesFiles {
+		overrides, err := chartutil.ReadValuesFile(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the %s values file: %v", valuesFile, err)
+		}
+		vals = chartutil.CoalesceTables(overrides, vals)
+	}
+
+	renderVals, err := chartutil.ToRenderValues(chrt, vals, chartutil.ReleaseOptions{
+		Name:      chrt.Name(),
+		Namespace: "default",
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute render values for the %s helm chart: %v", path, err)
+	}
+
+	rendered, err := engine.Render(chrt, renderVals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the %s helm chart: %v", path, err)
+	}
+
+	var buf bytes.Buffer
+	for name, content := range rendered {
+		if ext := filepath.Ext(name); ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		buf.WriteString("---\n")
+		buf.WriteString(content)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestIsKustomizeRoot(t *testing.T) {
+	t.Run("directory with kustomization.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "kustomization.yaml", "resources: []\n")
+
+		if !isKustomizeRoot(dir) {
+			t.Fatalf("isKustomizeRoot(%s) = false, want true", dir)
+		}
+	})
+
+	t.Run("directory with kustomization.yml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "kustomization.yml", "resources: []\n")
+
+		if !isKustomizeRoot(dir) {
+			t.Fatalf("isKustomizeRoot(%s) = false, want true", dir)
+		}
+	})
+
+	t.Run("plain manifest directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "deployment.yaml", "apiVersion: apps/v1\nkind: Deployment\n")
+
+		if isKustomizeRoot(dir) {
+			t.Fatalf("isKustomizeRoot(%s) = true, want false", dir)
+		}
+	})
+}
+
+func TestIsHelmChart(t *testing.T) {
+	t.Run("directory with Chart.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "Chart.yaml", "name: example\nversion: 0.1.0\n")
+
+		if !isHelmChart(dir) {
+			t.Fatalf("isHelmChart(%s) = false, want true", dir)
+		}
+	})
+
+	t.Run("packaged .tgz chart", func(t *testing.T) {
+		if !isHelmChart("/path/to/example-0.1.0.tgz") {
+			t.Fatalf("isHelmChart(.tgz) = false, want true")
+		}
+	})
+
+	t.Run("plain manifest directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "deployment.yaml", "apiVersion: apps/v1\nkind: Deployment\n")
+
+		if isHelmChart(dir) {
+			t.Fatalf("isHelmChart(%s) = true, want false", dir)
+		}
+	})
+}
+
+func TestRenderManifestDir(t *testing.T) {
+	t.Run("concatenates yaml files with --- separators", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.yaml", "kind: ServiceAccount\n")
+		writeFile(t, dir, "b.yaml", "kind: Deployment\n")
+
+		got, err := renderManifestDir(dir)
+		if err != nil {
+			t.Fatalf("renderManifestDir() returned error: %v", err)
+		}
+
+		docs, err := splitYAMLDocuments(got)
+		if err != nil {
+			t.Fatalf("failed to tokenize rendered output: %v", err)
+		}
+		if len(docs) != 2 {
+			t.Fatalf("expected 2 documents, got %d: %q", len(docs), got)
+		}
+	})
+
+	t.Run("non-yaml files are skipped", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.yaml", "kind: ServiceAccount\n")
+		writeFile(t, dir, "README.md", "# not a manifest\n")
+
+		got, err := renderManifestDir(dir)
+		if err != nil {
+			t.Fatalf("renderManifestDir() returned error: %v", err)
+		}
+
+		docs, err := splitYAMLDocuments(got)
+		if err != nil {
+			t.Fatalf("failed to tokenize rendered output: %v", err)
+		}
+		if len(docs) != 1 {
+			t.Fatalf("expected 1 document (README.md should be skipped), got %d: %q", len(docs), got)
+		}
+	})
+
+	t.Run("nested directories are walked", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, dir, "a.yaml", "kind: ServiceAccount\n")
+		sub := filepath.Join(dir, "rbac")
+		if err := os.Mkdir(sub, 0o755); err != nil {
+			t.Fatalf("failed to create subdirectory: %v", err)
+		}
+		writeFile(t, sub, "role.yaml", "kind: Role\n")
+
+		got, err := renderManifestDir(dir)
+		if err != nil {
+			t.Fatalf("renderManifestDir() returned error: %v", err)
+		}
+
+		docs, err := splitYAMLDocuments(got)
+		if err != nil {
+			t.Fatalf("failed to tokenize rendered output: %v", err)
+		}
+		if len(docs) != 2 {
+			t.Fatalf("expected 2 documents, got %d: %q", len(docs), got)
+		}
+	})
+}